@@ -0,0 +1,128 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package filelock provides a platform-independent API for advisory file
+// locking. Calls to functions in this package on platforms that do not
+// support advisory locks will return errors for which IsNotSupported
+// returns true.
+package filelock
+
+import (
+	"errors"
+	"os"
+)
+
+// A File provides the minimal set of methods required to lock an open file.
+// File implementations must be usable as map keys.
+// The usual implementation is *os.File.
+type File interface {
+	// Name returns the name of the file.
+	Name() string
+
+	// Fd returns a valid file descriptor.
+	// (If the File is an *os.File, it must not be closed.)
+	Fd() uintptr
+
+	// Stat returns the FileInfo structure describing file.
+	Stat() (os.FileInfo, error)
+}
+
+// ErrLocked indicates that TryLock or TryRLock failed because the file is
+// already locked, either by this process or by another one.
+var ErrLocked = errors.New("file already locked")
+
+// ErrConflict indicates that Upgrade could not take the write lock because
+// another locker holds a lock on the file, leaving f still read-locked.
+var ErrConflict = errors.New("another locker acquired the write lock first")
+
+// ErrNotSupported indicates that the host platform does not support the
+// requested locking operation.
+var ErrNotSupported = errors.New("file locking not supported")
+
+// Lock places an advisory write lock on the file, blocking until it can be
+// locked.
+//
+// If Lock returns nil, no other process will be able to place a read or write
+// lock on the file until this process exits, closes f, or calls Unlock on it.
+//
+// If f's descriptor is already read- or write-locked, the behavior of Lock is
+// unspecified.
+//
+// Closing the file may or may not release the lock promptly. Callers should
+// ensure that Unlock is always called when Lock succeeds.
+func Lock(f File) error {
+	return lock(f, writeLock)
+}
+
+// RLock places an advisory read lock on the file, blocking until it can be locked.
+//
+// If RLock returns nil, no other process will be able to place a write lock on
+// the file until this process exits, closes f, or calls Unlock on it.
+//
+// If f is already read- or write-locked, the behavior of RLock is unspecified.
+//
+// Closing the file may or may not release the lock promptly. Callers should
+// ensure that Unlock is always called if RLock succeeds.
+func RLock(f File) error {
+	return lock(f, readLock)
+}
+
+// TryLock places an advisory write lock on the file, failing with ErrLocked
+// instead of blocking if the file is already locked.
+func TryLock(f File) error {
+	return trylock(f, writeLock)
+}
+
+// TryRLock places an advisory read lock on the file, failing with ErrLocked
+// instead of blocking if the file is already write-locked.
+func TryRLock(f File) error {
+	return trylock(f, readLock)
+}
+
+// Unlock removes an advisory lock placed on f by this process.
+//
+// The caller must not attempt to unlock a file that is not locked.
+func Unlock(f File) error {
+	return unlock(f)
+}
+
+// Upgrade attempts to promote an existing read lock on f to a write lock.
+//
+// The caller must already hold a read lock on f.
+//
+// The conversion is not atomic on any supported platform: the read lock is
+// released before the write lock is requested, so another locker may acquire
+// a lock in the gap. If another locker holds a lock on the file, Upgrade
+// returns ErrConflict and f remains read-locked (Upgrade blocks until it can
+// take the read lock back). On platforms that do not support locking at all,
+// Upgrade returns ErrNotSupported.
+func Upgrade(f File) error {
+	return upgrade(f)
+}
+
+// Downgrade demotes an existing write lock on f back to a read lock.
+//
+// The caller must already hold a write lock on f.
+func Downgrade(f File) error {
+	return downgrade(f)
+}
+
+// String returns the name of the function corresponding to lt
+// (Lock, RLock, or Unlock).
+func (lt lockType) String() string {
+	switch lt {
+	case readLock:
+		return "RLock"
+	case writeLock:
+		return "Lock"
+	default:
+		return "Unlock"
+	}
+}
+
+// IsNotSupported returns a boolean indicating whether the error is known to
+// report that a function is not supported (possibly for a specific input).
+func IsNotSupported(err error) bool {
+	return errors.Is(err, ErrNotSupported)
+}
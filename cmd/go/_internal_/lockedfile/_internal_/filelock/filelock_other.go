@@ -0,0 +1,53 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build !darwin && !dragonfly && !freebsd && !linux && !netbsd && !openbsd && !windows
+// +build !darwin,!dragonfly,!freebsd,!linux,!netbsd,!openbsd,!windows
+
+package filelock
+
+import "os"
+
+type lockType int8
+
+const (
+	readLock	lockType	= iota + 1
+	writeLock
+)
+
+func lock(f File, lt lockType) error {
+	return &os.PathError{
+		Op:	lt.String(),
+		Path:	f.Name(),
+		Err:	ErrNotSupported,
+	}
+}
+
+func trylock(f File, lt lockType) error {
+	return lock(f, lt)
+}
+
+func unlock(f File) error {
+	return &os.PathError{
+		Op:	"Unlock",
+		Path:	f.Name(),
+		Err:	ErrNotSupported,
+	}
+}
+
+func upgrade(f File) error {
+	return &os.PathError{
+		Op:	"Upgrade",
+		Path:	f.Name(),
+		Err:	ErrNotSupported,
+	}
+}
+
+func downgrade(f File) error {
+	return &os.PathError{
+		Op:	"Downgrade",
+		Path:	f.Name(),
+		Err:	ErrNotSupported,
+	}
+}
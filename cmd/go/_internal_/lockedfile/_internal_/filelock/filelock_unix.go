@@ -0,0 +1,90 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build darwin || dragonfly || freebsd || linux || netbsd || openbsd
+// +build darwin dragonfly freebsd linux netbsd openbsd
+
+package filelock
+
+import (
+	"os"
+	"syscall"
+)
+
+type lockType int
+
+const (
+	readLock	lockType	= syscall.LOCK_SH
+	writeLock	lockType	= syscall.LOCK_EX
+)
+
+func lock(f File, lt lockType) (err error) {
+	for {
+		err = syscall.Flock(int(f.Fd()), int(lt))
+		if err != syscall.EINTR {
+			break
+		}
+	}
+	if err != nil {
+		return &os.PathError{
+			Op:	lt.String(),
+			Path:	f.Name(),
+			Err:	err,
+		}
+	}
+	return nil
+}
+
+func trylock(f File, lt lockType) error {
+	err := syscall.Flock(int(f.Fd()), int(lt)|syscall.LOCK_NB)
+	if err == nil {
+		return nil
+	}
+	op := "Try" + lt.String()
+	if err == syscall.EWOULDBLOCK {
+		return &os.PathError{Op: op, Path: f.Name(), Err: ErrLocked}
+	}
+	return &os.PathError{Op: op, Path: f.Name(), Err: err}
+}
+
+func unlock(f File) error {
+	return lock(f, syscall.LOCK_UN)
+}
+
+// flock(2) converting an already-held lock to a different mode first drops
+// the lock and then re-acquires it, so a pending waiter can be granted the
+// lock in the gap: the conversion is not atomic. upgrade therefore uses
+// LOCK_NB and reports ErrConflict (rather than blocking forever) if another
+// locker holds a lock on the file. A failed conversion has already dropped
+// the shared lock, so upgrade takes the read lock again before reporting
+// ErrConflict, matching the documented non-atomic-upgrade contract.
+func upgrade(f File) error {
+	err := syscall.Flock(int(f.Fd()), int(writeLock)|syscall.LOCK_NB)
+	if err == nil {
+		return nil
+	}
+	if err == syscall.EWOULDBLOCK {
+		if err := lock(f, readLock); err != nil {
+			return err
+		}
+		return &os.PathError{Op: "Upgrade", Path: f.Name(), Err: ErrConflict}
+	}
+	return &os.PathError{Op: "Upgrade", Path: f.Name(), Err: err}
+}
+
+// downgrade demotes a write lock back to a read lock. Unlike upgrade, it
+// cannot fail to a competing locker (a read lock can always be granted), so
+// it blocks rather than requiring LOCK_NB.
+func downgrade(f File) (err error) {
+	for {
+		err = syscall.Flock(int(f.Fd()), int(readLock))
+		if err != syscall.EINTR {
+			break
+		}
+	}
+	if err != nil {
+		return &os.PathError{Op: "Downgrade", Path: f.Name(), Err: err}
+	}
+	return nil
+}
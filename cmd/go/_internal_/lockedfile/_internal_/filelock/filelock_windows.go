@@ -0,0 +1,126 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build windows
+// +build windows
+
+package filelock
+
+import (
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+var (
+	modkernel32		= syscall.NewLazyDLL("kernel32.dll")
+	procLockFileEx		= modkernel32.NewProc("LockFileEx")
+	procUnlockFileEx	= modkernel32.NewProc("UnlockFileEx")
+)
+
+const (
+	lockfileExclusiveLock	= 0x2
+	lockfileFailImmediately	= 0x1
+
+	reserved	= 0
+	allBytes	= ^uint32(0)
+
+	errnoLockViolation	= syscall.Errno(33)
+)
+
+type lockType uint32
+
+const (
+	readLock	lockType	= 0
+	writeLock	lockType	= lockfileExclusiveLock
+)
+
+func lockFileEx(h syscall.Handle, flags uint32, ol *syscall.Overlapped) error {
+	r, _, e := procLockFileEx.Call(uintptr(h), uintptr(flags), uintptr(reserved), uintptr(allBytes), uintptr(allBytes), uintptr(unsafe.Pointer(ol)))
+	if r == 0 {
+		return e
+	}
+	return nil
+}
+
+func unlockFileEx(h syscall.Handle, ol *syscall.Overlapped) error {
+	r, _, e := procUnlockFileEx.Call(uintptr(h), uintptr(reserved), uintptr(allBytes), uintptr(allBytes), uintptr(unsafe.Pointer(ol)))
+	if r == 0 {
+		return e
+	}
+	return nil
+}
+
+func lock(f File, lt lockType) error {
+	// Per https://golang.org/issue/19098, “Programs currently expect the Fd
+	// method to return a handle that uses ordinary synchronous I/O.”
+	// However, LockFileEx still requires an OVERLAPPED structure,
+	// which contains the file offset of the beginning of the lock range.
+	// We want to lock the entire file, so we leave the offset as zero.
+	ol := new(syscall.Overlapped)
+	if err := lockFileEx(syscall.Handle(f.Fd()), uint32(lt), ol); err != nil {
+		return &os.PathError{Op: lt.String(), Path: f.Name(), Err: err}
+	}
+	return nil
+}
+
+func trylock(f File, lt lockType) error {
+	ol := new(syscall.Overlapped)
+	op := "Try" + lt.String()
+	err := lockFileEx(syscall.Handle(f.Fd()), uint32(lt)|lockfileFailImmediately, ol)
+	if err == nil {
+		return nil
+	}
+	if err == errnoLockViolation {
+		return &os.PathError{Op: op, Path: f.Name(), Err: ErrLocked}
+	}
+	return &os.PathError{Op: op, Path: f.Name(), Err: err}
+}
+
+func unlock(f File) error {
+	ol := new(syscall.Overlapped)
+	if err := unlockFileEx(syscall.Handle(f.Fd()), ol); err != nil {
+		return &os.PathError{Op: "Unlock", Path: f.Name(), Err: err}
+	}
+	return nil
+}
+
+// upgrade promotes a read lock to a write lock. LockFileEx has no facility
+// for converting a lock in place, so this unlocks and then re-locks the
+// file; another locker can slip in during the gap between the two calls, in
+// which case upgrade reports ErrConflict and leaves the file read-locked.
+func upgrade(f File) error {
+	h := syscall.Handle(f.Fd())
+
+	if err := unlockFileEx(h, new(syscall.Overlapped)); err != nil {
+		return &os.PathError{Op: "Upgrade", Path: f.Name(), Err: err}
+	}
+
+	err := lockFileEx(h, uint32(writeLock)|lockfileFailImmediately, new(syscall.Overlapped))
+	if err == nil {
+		return nil
+	}
+
+	// Best effort: put the read lock back so the file is left in the state
+	// this method documents, even though we lost the race for the write lock.
+	lockFileEx(h, uint32(readLock), new(syscall.Overlapped))
+
+	if err == errnoLockViolation {
+		return &os.PathError{Op: "Upgrade", Path: f.Name(), Err: ErrConflict}
+	}
+	return &os.PathError{Op: "Upgrade", Path: f.Name(), Err: err}
+}
+
+// downgrade demotes a write lock back to a read lock.
+func downgrade(f File) error {
+	h := syscall.Handle(f.Fd())
+
+	if err := unlockFileEx(h, new(syscall.Overlapped)); err != nil {
+		return &os.PathError{Op: "Downgrade", Path: f.Name(), Err: err}
+	}
+	if err := lockFileEx(h, uint32(readLock), new(syscall.Overlapped)); err != nil {
+		return &os.PathError{Op: "Downgrade", Path: f.Name(), Err: err}
+	}
+	return nil
+}
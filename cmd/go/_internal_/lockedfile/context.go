@@ -0,0 +1,85 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package lockedfile
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"os"
+	"time"
+)
+
+// OpenFileContext is like OpenFile, but fails with ctx.Err() instead of
+// blocking indefinitely once ctx is done.
+//
+// The lock-acquisition syscalls used by this package are not interruptible by
+// a Go context, so OpenFileContext polls TryOpenFile with exponential backoff
+// capped at 100ms. As a result, cancellation is only as prompt as that
+// backoff interval: ctx.Err() may not be observed until up to 100ms after ctx
+// is done.
+func OpenFileContext(ctx context.Context, name string, flag int, perm os.FileMode) (*File, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	const maxBackoff = 100 * time.Millisecond
+	backoff := 1 * time.Millisecond
+	for {
+		f, err := TryOpenFile(name, flag, perm)
+		if err == nil {
+			return f, nil
+		}
+		if !IsLocked(err) {
+			return nil, err
+		}
+
+		timer := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+
+		backoff *= 2
+		if backoff > maxBackoff {
+			backoff = maxBackoff
+		}
+	}
+}
+
+// ReadContext is like Read, but fails with ctx.Err() instead of blocking
+// indefinitely once ctx is done.
+func ReadContext(ctx context.Context, name string) ([]byte, error) {
+	f, err := OpenFileContext(ctx, name, os.O_RDONLY, 0)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return ioutil.ReadAll(f)
+}
+
+// WriteContext is like Write, but fails with ctx.Err() instead of blocking
+// indefinitely once ctx is done.
+func WriteContext(ctx context.Context, name string, content io.Reader, perm os.FileMode) (err error) {
+	f, err := OpenFileContext(ctx, name, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, perm)
+	if err != nil {
+		return err
+	}
+
+	_, err = io.Copy(f, content)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// EditContext is like Edit, but fails with ctx.Err() instead of blocking
+// indefinitely once ctx is done.
+func EditContext(ctx context.Context, name string) (*File, error) {
+	return OpenFileContext(ctx, name, os.O_RDWR|os.O_CREATE, 0666)
+}
@@ -0,0 +1,115 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package lockedfile_test
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/hensur/gomodules-extracted/cmd/go/_internal_/lockedfile"
+)
+
+func mustTempDir(t *testing.T) (dir string, remove func()) {
+	t.Helper()
+
+	dir, err := ioutil.TempDir("", filepath.Base(t.Name()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return dir, func() { os.RemoveAll(dir) }
+}
+
+const (
+	quiescent		= 10 * time.Millisecond
+	probablyStillBlocked	= 10 * time.Second
+)
+
+const (
+	mutexChildEnv		= "LOCKEDFILE_TEST_MUTEX_CHILD"
+	mutexChildPathEnv	= "LOCKEDFILE_TEST_MUTEX_PATH"
+	mutexChildMarkerEnv	= "LOCKEDFILE_TEST_MUTEX_MARKER"
+)
+
+// TestMutexExcludesAcrossProcesses locks a Mutex in this process, then spawns
+// a child process that tries to lock the same path, and verifies that the
+// child blocks until this process releases the lock.
+func TestMutexExcludesAcrossProcesses(t *testing.T) {
+	if os.Getenv(mutexChildEnv) != "" {
+		mutexChildMain()
+		return
+	}
+
+	dir, remove := mustTempDir(t)
+	defer remove()
+	path := filepath.Join(dir, "lock")
+	marker := filepath.Join(dir, "child-locked")
+
+	mu := lockedfile.MutexAt(path)
+	unlock, err := mu.Lock()
+	if err != nil {
+		t.Fatalf("mu.Lock: %v", err)
+	}
+
+	cmd := exec.Command(os.Args[0], "-test.run=^"+t.Name()+"$")
+	cmd.Env = append(os.Environ(),
+		mutexChildEnv+"=1",
+		mutexChildPathEnv+"="+path,
+		mutexChildMarkerEnv+"="+marker,
+	)
+
+	done := make(chan struct{})
+	var out []byte
+	var runErr error
+	go func() {
+		out, runErr = cmd.CombinedOutput()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		t.Fatalf("child process unexpectedly did not block:\n%s", out)
+	case <-time.After(quiescent):
+	}
+
+	unlock()
+
+	select {
+	case <-done:
+	case <-time.After(probablyStillBlocked):
+		t.Fatal("child process did not unblock after the parent released the lock")
+	}
+	if runErr != nil {
+		t.Fatalf("child process failed: %v\n%s", runErr, out)
+	}
+	if _, err := os.Stat(marker); err != nil {
+		t.Fatalf("child process did not record acquiring the lock: %v", err)
+	}
+}
+
+// mutexChildMain runs as the child process spawned by
+// TestMutexExcludesAcrossProcesses: it blocks until it can lock the path
+// named by mutexChildPathEnv, then creates the file named by
+// mutexChildMarkerEnv to record success.
+func mutexChildMain() {
+	path := os.Getenv(mutexChildPathEnv)
+	marker := os.Getenv(mutexChildMarkerEnv)
+
+	unlock, err := lockedfile.MutexAt(path).Lock()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "child: Lock: %v\n", err)
+		os.Exit(1)
+	}
+	defer unlock()
+
+	if err := ioutil.WriteFile(marker, []byte("locked"), 0666); err != nil {
+		fmt.Fprintf(os.Stderr, "child: WriteFile: %v\n", err)
+		os.Exit(1)
+	}
+}
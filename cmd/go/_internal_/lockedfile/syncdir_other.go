@@ -0,0 +1,14 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build !darwin && !dragonfly && !freebsd && !linux && !netbsd && !openbsd
+// +build !darwin,!dragonfly,!freebsd,!linux,!netbsd,!openbsd
+
+package lockedfile
+
+// syncDir is a no-op on platforms that do not support (or do not need)
+// fsyncing a directory to make a preceding rename into it durable.
+func syncDir(dir string) error {
+	return nil
+}
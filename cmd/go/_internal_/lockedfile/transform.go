@@ -0,0 +1,167 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package lockedfile
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// Transform locks a sidecar sentinel file alongside name, reads name's
+// current contents, and passes them to fn. If fn returns a nil error,
+// Transform writes the returned contents to a temporary file in the same
+// directory, fsyncs it, and renames it over name, then fsyncs the containing
+// directory on Unix. If any step before the rename fails, the temporary file
+// is removed and name is left untouched.
+//
+// The lock is held on the sentinel rather than on name itself, because name
+// is replaced (not written in place): a locker that held name's own inode
+// across the rename would be left holding a stale, unlinked file once a
+// writer renamed a new one into place.
+//
+// This gives callers a crash-safe, concurrency-safe update: unlike Write,
+// which truncates the file in place and can leave a partial file behind if
+// the program is interrupted mid-write, Transform either replaces the file
+// in its entirety or not at all.
+func Transform(name string, fn func(old []byte) (new []byte, err error)) (err error) {
+	unlock, err := MutexAt(name + ".lock").Lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	old, perm, err := readExisting(name)
+	if err != nil {
+		return err
+	}
+
+	data, err := fn(old)
+	if err != nil {
+		return err
+	}
+
+	return writeAndRename(name, perm, func(w io.Writer) error {
+		_, err := w.Write(data)
+		return err
+	})
+}
+
+// TransformFile is a streaming variant of Transform: instead of buffering the
+// old and new contents in memory, it invokes fn with a reader over name's
+// current contents and a writer for the new ones.
+func TransformFile(name string, fn func(r io.Reader, w io.Writer) error) (err error) {
+	unlock, err := MutexAt(name + ".lock").Lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	r, perm, err := openExisting(name)
+	if err != nil {
+		return err
+	}
+	if c, ok := r.(io.Closer); ok {
+		defer c.Close()
+	}
+
+	return writeAndRename(name, perm, func(w io.Writer) error {
+		return fn(r, w)
+	})
+}
+
+// defaultPerm is the permission Transform and TransformFile give a file that
+// does not yet exist. It is the mode Edit passes when creating a file, but
+// writeAndRename applies it with Chmod, so the umask does not reduce it.
+const defaultPerm = 0666
+
+// readExisting returns the current contents and permissions of name, or a
+// nil slice and defaultPerm if name does not yet exist.
+func readExisting(name string) (old []byte, perm os.FileMode, err error) {
+	old, err = ioutil.ReadFile(name)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, defaultPerm, nil
+		}
+		return nil, 0, err
+	}
+
+	fi, err := os.Stat(name)
+	if err != nil {
+		return nil, 0, err
+	}
+	return old, fi.Mode(), nil
+}
+
+// openExisting is the streaming analogue of readExisting: it returns a
+// reader over name's current contents (an empty reader if name does not yet
+// exist), and its permissions. If the returned reader is an io.Closer, the
+// caller is responsible for closing it.
+func openExisting(name string) (io.Reader, os.FileMode, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return bytes.NewReader(nil), defaultPerm, nil
+		}
+		return nil, 0, err
+	}
+
+	fi, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, 0, err
+	}
+	return f, fi.Mode(), nil
+}
+
+// writeAndRename calls write with a temporary file created alongside name,
+// fsyncs that file, and renames it over name. On Unix it also fsyncs name's
+// parent directory, since there a rename is not guaranteed to be durable
+// until the directory that contains it is synced too; elsewhere syncDir is a
+// no-op.
+//
+// The temporary file is chmoded to perm before the rename, so that the
+// replacement does not silently reset name's permissions to ioutil.TempFile's
+// default mode.
+func writeAndRename(name string, perm os.FileMode, write func(io.Writer) error) (err error) {
+	dir := filepath.Dir(name)
+	tmp, err := ioutil.TempFile(dir, filepath.Base(name)+".tmp*")
+	if err != nil {
+		return err
+	}
+
+	removeTmp := true
+	defer func() {
+		if removeTmp {
+			os.Remove(tmp.Name())
+		}
+	}()
+
+	if err := tmp.Chmod(perm); err != nil {
+		tmp.Close()
+		return err
+	}
+
+	if err := write(tmp); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	if err := os.Rename(tmp.Name(), name); err != nil {
+		return err
+	}
+	removeTmp = false
+
+	return syncDir(dir)
+}
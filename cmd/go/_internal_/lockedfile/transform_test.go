@@ -0,0 +1,56 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package lockedfile_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hensur/gomodules-extracted/cmd/go/_internal_/lockedfile"
+)
+
+// TestTransformPanicLeavesFileIntact verifies that if fn panics partway
+// through a Transform, the file named is left with its original contents and
+// mode rather than a partial or missing update.
+func TestTransformPanicLeavesFileIntact(t *testing.T) {
+	dir, remove := mustTempDir(t)
+	defer remove()
+	path := filepath.Join(dir, "data.txt")
+
+	const original = "original contents\n"
+	if err := lockedfile.Write(path, strings.NewReader(original), 0644); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+
+	func() {
+		defer func() {
+			if r := recover(); r == nil {
+				t.Error("fn's panic did not propagate out of Transform")
+			}
+		}()
+		lockedfile.Transform(path, func(old []byte) ([]byte, error) {
+			panic("boom")
+		})
+	}()
+
+	got, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile: %v", err)
+	}
+	if string(got) != original {
+		t.Fatalf("file contents after panic = %q; want unchanged %q", got, original)
+	}
+
+	fi, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("Stat: %v", err)
+	}
+	if fi.Mode().Perm() != 0644 {
+		t.Fatalf("file mode after panic = %v; want unchanged 0644", fi.Mode().Perm())
+	}
+}
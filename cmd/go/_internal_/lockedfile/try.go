@@ -0,0 +1,73 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package lockedfile
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"runtime"
+
+	"github.com/hensur/gomodules-extracted/cmd/go/_internal_/lockedfile/_internal_/filelock"
+)
+
+// TryOpenFile is like OpenFile, but instead of blocking when the file is
+// already locked, it returns immediately with an error for which IsLocked
+// reports true.
+func TryOpenFile(name string, flag int, perm os.FileMode) (*File, error) {
+	var (
+		f	= new(File)
+		err	error
+	)
+	f.osFile.File, err = tryOpenFile(name, flag, perm)
+	if err != nil {
+		return nil, err
+	}
+
+	// See the comment in OpenFile: we want to hold locks for as little time as
+	// possible, so use a finalizer to report a missing Close on a best-effort
+	// basis.
+	runtime.SetFinalizer(f, func(f *File) {
+		panic(fmt.Sprintf("lockedfile.File %s became unreachable without a call to Close", f.Name()))
+	})
+
+	return f, nil
+}
+
+// IsLocked reports whether err is the error returned by TryOpenFile (or one
+// of its TryRead/TryWrite wrappers) when the file is already locked by
+// another process or descriptor.
+func IsLocked(err error) bool {
+	return errors.Is(err, filelock.ErrLocked)
+}
+
+// TryRead is like Read, but returns an error for which IsLocked reports true
+// instead of blocking if the file is already locked.
+func TryRead(name string) ([]byte, error) {
+	f, err := TryOpenFile(name, os.O_RDONLY, 0)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return ioutil.ReadAll(f)
+}
+
+// TryWrite is like Write, but returns an error for which IsLocked reports
+// true instead of blocking if the file is already locked.
+func TryWrite(name string, content io.Reader, perm os.FileMode) (err error) {
+	f, err := TryOpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, perm)
+	if err != nil {
+		return err
+	}
+
+	_, err = io.Copy(f, content)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
@@ -0,0 +1,107 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package lockedfile_test
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/hensur/gomodules-extracted/cmd/go/_internal_/lockedfile"
+)
+
+const (
+	tryChildEnv		= "LOCKEDFILE_TEST_TRY_CHILD"
+	tryChildPathEnv		= "LOCKEDFILE_TEST_TRY_PATH"
+	tryChildReadyEnv	= "LOCKEDFILE_TEST_TRY_READY"
+)
+
+// TestTryOpenFileFailsImmediatelyWhenLockedByChild spawns a child process that
+// holds name locked via Edit, then verifies that TryOpenFile in this process
+// fails right away with an error for which IsLocked reports true, rather than
+// blocking until the child exits.
+func TestTryOpenFileFailsImmediatelyWhenLockedByChild(t *testing.T) {
+	if os.Getenv(tryChildEnv) != "" {
+		tryChildMain()
+		return
+	}
+
+	dir, remove := mustTempDir(t)
+	defer remove()
+	path := filepath.Join(dir, "locked")
+	ready := filepath.Join(dir, "child-ready")
+
+	cmd := exec.Command(os.Args[0], "-test.run=^"+t.Name()+"$")
+	cmd.Env = append(os.Environ(),
+		tryChildEnv+"=1",
+		tryChildPathEnv+"="+path,
+		tryChildReadyEnv+"="+ready,
+	)
+	if err := cmd.Start(); err != nil {
+		t.Fatalf("starting child process: %v", err)
+	}
+	defer func() {
+		cmd.Process.Kill()
+		cmd.Wait()
+	}()
+
+	waitForFile(t, ready)
+
+	start := time.Now()
+	_, err := lockedfile.TryOpenFile(path, os.O_RDWR, 0666)
+	elapsed := time.Since(start)
+
+	if err == nil {
+		t.Fatal("TryOpenFile unexpectedly succeeded while the child held the lock")
+	}
+	if !lockedfile.IsLocked(err) {
+		t.Fatalf("TryOpenFile error = %v; want an error for which IsLocked reports true", err)
+	}
+	if elapsed > probablyStillBlocked {
+		t.Errorf("TryOpenFile took %v to fail; want it to fail immediately without blocking", elapsed)
+	}
+}
+
+// tryChildMain runs as the child process spawned by
+// TestTryOpenFileFailsImmediatelyWhenLockedByChild: it locks the path named
+// by tryChildPathEnv, signals readiness by creating the file named by
+// tryChildReadyEnv, and then blocks until killed by the parent.
+func tryChildMain() {
+	path := os.Getenv(tryChildPathEnv)
+	ready := os.Getenv(tryChildReadyEnv)
+
+	f, err := lockedfile.Edit(path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "child: Edit: %v\n", err)
+		os.Exit(1)
+	}
+	defer f.Close()
+
+	if err := ioutil.WriteFile(ready, []byte("ready"), 0666); err != nil {
+		fmt.Fprintf(os.Stderr, "child: WriteFile: %v\n", err)
+		os.Exit(1)
+	}
+
+	select {}
+}
+
+func waitForFile(t *testing.T, path string) {
+	t.Helper()
+
+	deadline := time.Now().Add(probablyStillBlocked)
+	for {
+		if _, err := os.Stat(path); err == nil {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s to be created", path)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
@@ -0,0 +1,37 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package lockedfile
+
+import (
+	"errors"
+
+	"github.com/hensur/gomodules-extracted/cmd/go/_internal_/lockedfile/_internal_/filelock"
+)
+
+// Upgrade attempts to promote a read-locked File to a write lock, so that a
+// caller that opened the file with Open (or OpenFile with os.O_RDONLY) to
+// inspect its contents can go on to rewrite it without first closing and
+// reopening it.
+//
+// The underlying lock cannot be converted without a gap on any supported
+// platform, so another locker may hold or acquire a lock first; in that case
+// Upgrade returns an error for which IsConflict reports true, and f remains
+// read-locked. On Plan 9, Upgrade always fails with an
+// error for which filelock.IsNotSupported reports true.
+func (f *File) Upgrade() error {
+	return filelock.Upgrade(f.osFile.File)
+}
+
+// Downgrade demotes a write-locked File back to a read lock, for example
+// after Upgrade, once the caller no longer needs to write.
+func (f *File) Downgrade() error {
+	return filelock.Downgrade(f.osFile.File)
+}
+
+// IsConflict reports whether err is the error returned by Upgrade when
+// another locker holds a lock on the file.
+func IsConflict(err error) bool {
+	return errors.Is(err, filelock.ErrConflict)
+}